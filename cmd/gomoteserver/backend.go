@@ -0,0 +1,175 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.20 && (linux || darwin)
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	gomotepb "golang.org/x/build/internal/gomote/protos"
+)
+
+// swarmingBuilderSuffix marks builder types that -backend=hybrid routes to
+// the swarming backend; every other builder type goes to the buildlet
+// pool backend.
+const swarmingBuilderSuffix = "-swarming"
+
+// hybridGomoteService is a gomotepb.GomoteServiceServer that spans a
+// buildlet-pool backend and a swarming backend, so a single gomote
+// endpoint can serve both as Go's trybots migrate to LUCI.
+//
+// CreateInstance routes by builder type and records which backend ended
+// up owning the new session's ID; every other per-session RPC looks
+// that ID back up so it reaches the backend that actually holds the
+// session, not just whichever backend happens to be embedded as the
+// default.
+type hybridGomoteService struct {
+	gomotepb.GomoteServiceServer // buildlet backend; default for sessions we haven't seen
+
+	swarming gomotepb.GomoteServiceServer
+
+	mu       sync.Mutex
+	backends map[string]gomotepb.GomoteServiceServer // gomote ID -> owning backend
+}
+
+func newHybridGomoteService(buildlet, swarming gomotepb.GomoteServiceServer) *hybridGomoteService {
+	return &hybridGomoteService{
+		GomoteServiceServer: buildlet,
+		swarming:            swarming,
+		backends:            make(map[string]gomotepb.GomoteServiceServer),
+	}
+}
+
+// backendForBuilderType returns the backend that should create a new
+// session of builderType.
+func (h *hybridGomoteService) backendForBuilderType(builderType string) gomotepb.GomoteServiceServer {
+	if strings.Contains(builderType, swarmingBuilderSuffix) {
+		return h.swarming
+	}
+	return h.GomoteServiceServer
+}
+
+// sessionScoped is implemented by every gomotepb request that names an
+// existing gomote session.
+type sessionScoped interface {
+	GetGomoteId() string
+}
+
+// backendFor returns the backend recorded as owning req's session.
+// Sessions we never saw created (e.g. the server restarted) fall back to
+// the buildlet backend, matching behavior before hybrid routing existed.
+func (h *hybridGomoteService) backendFor(req sessionScoped) gomotepb.GomoteServiceServer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if b, ok := h.backends[req.GetGomoteId()]; ok {
+		return b
+	}
+	return h.GomoteServiceServer
+}
+
+func (h *hybridGomoteService) own(gomoteID string, backend gomotepb.GomoteServiceServer) {
+	h.mu.Lock()
+	h.backends[gomoteID] = backend
+	h.mu.Unlock()
+}
+
+func (h *hybridGomoteService) forget(gomoteID string) {
+	h.mu.Lock()
+	delete(h.backends, gomoteID)
+	h.mu.Unlock()
+}
+
+func (h *hybridGomoteService) Instances(ctx context.Context, req *gomotepb.InstancesRequest) (*gomotepb.InstancesResponse, error) {
+	buildletResp, err := h.GomoteServiceServer.Instances(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	swarmingResp, err := h.swarming.Instances(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &gomotepb.InstancesResponse{
+		Instances: append(buildletResp.GetInstances(), swarmingResp.GetInstances()...),
+	}, nil
+}
+
+func (h *hybridGomoteService) CreateInstance(req *gomotepb.CreateInstanceRequest, stream gomotepb.GomoteService_CreateInstanceServer) error {
+	backend := h.backendForBuilderType(req.GetBuilderType())
+	return backend.CreateInstance(req, &trackingCreateInstanceStream{
+		GomoteService_CreateInstanceServer: stream,
+		own:                                func(id string) { h.own(id, backend) },
+	})
+}
+
+// trackingCreateInstanceStream wraps the client's CreateInstance stream
+// so the hybrid dispatcher learns a new session's ID as soon as the
+// backend reports it, without the backend needing any hybrid-specific
+// code.
+type trackingCreateInstanceStream struct {
+	gomotepb.GomoteService_CreateInstanceServer
+	own      func(gomoteID string)
+	recorded bool
+}
+
+func (s *trackingCreateInstanceStream) Send(resp *gomotepb.CreateInstanceResponse) error {
+	if !s.recorded {
+		if id := resp.GetInstance().GetGomoteId(); id != "" {
+			s.own(id)
+			s.recorded = true
+		}
+	}
+	return s.GomoteService_CreateInstanceServer.Send(resp)
+}
+
+func (h *hybridGomoteService) DestroyInstance(ctx context.Context, req *gomotepb.DestroyInstanceRequest) (*gomotepb.DestroyInstanceResponse, error) {
+	resp, err := h.backendFor(req).DestroyInstance(ctx, req)
+	if err == nil {
+		h.forget(req.GetGomoteId())
+	}
+	return resp, err
+}
+
+func (h *hybridGomoteService) InstanceAlive(ctx context.Context, req *gomotepb.InstanceAliveRequest) (*gomotepb.InstanceAliveResponse, error) {
+	return h.backendFor(req).InstanceAlive(ctx, req)
+}
+
+func (h *hybridGomoteService) Exec(req *gomotepb.ExecRequest, stream gomotepb.GomoteService_ExecServer) error {
+	return h.backendFor(req).Exec(req, stream)
+}
+
+func (h *hybridGomoteService) ReadTGZToURL(ctx context.Context, req *gomotepb.ReadTGZToURLRequest) (*gomotepb.ReadTGZToURLResponse, error) {
+	return h.backendFor(req).ReadTGZToURL(ctx, req)
+}
+
+func (h *hybridGomoteService) WriteTGZFromURL(ctx context.Context, req *gomotepb.WriteTGZFromURLRequest) (*gomotepb.WriteTGZFromURLResponse, error) {
+	return h.backendFor(req).WriteTGZFromURL(ctx, req)
+}
+
+func (h *hybridGomoteService) ListDirectory(ctx context.Context, req *gomotepb.ListDirectoryRequest) (*gomotepb.ListDirectoryResponse, error) {
+	return h.backendFor(req).ListDirectory(ctx, req)
+}
+
+func (h *hybridGomoteService) RemoveFiles(ctx context.Context, req *gomotepb.RemoveFilesRequest) (*gomotepb.RemoveFilesResponse, error) {
+	return h.backendFor(req).RemoveFiles(ctx, req)
+}
+
+// hybridReverseHandler returns the /reverse handler for -backend=hybrid:
+// a reverse buildlet or bot registers its builder type(s) in the same
+// "builder" form value CreateInstance matches on, so dispatching here
+// uses the same swarmingBuilderSuffix convention, and both backends'
+// machines can register against the one endpoint.
+func hybridReverseHandler(buildletReverse, swarmingReverse http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.FormValue("builder"), swarmingBuilderSuffix) {
+			swarmingReverse(w, r)
+			return
+		}
+		buildletReverse(w, r)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"golang.org/x/build/internal/gomote"
 	gomotepb "golang.org/x/build/internal/gomote/protos"
 	"golang.org/x/build/internal/https"
+	"golang.org/x/build/internal/rendezvous"
 	"golang.org/x/build/internal/secret"
 	"golang.org/x/build/internal/swarmclient"
 	"google.golang.org/api/option"
@@ -36,6 +37,22 @@ var (
 	sshAddr      = flag.String("ssh_addr", ":2222", "Address the gomote SSH server should listen on")
 	buildEnvName = flag.String("env", "", "The build environment configuration to use. Not required if running in dev mode locally or prod mode on GCE.")
 	mode         = flag.String("mode", "", "Valid modes are 'dev', 'prod', or '' for auto-detect. dev means localhost development, not be confused with staging on go-dashboard-dev, which is still the 'prod' mode.")
+
+	authMode      = flag.String("auth", "", "Authentication mode to use: 'iap', 'jwt', or '' for auto-detect (IAP on GCE, open otherwise). 'jwt' lets the server run behind a plain HTTPS proxy, outside of GCP/IAP.")
+	jwtIssuer     = flag.String("jwt_issuer", "", "Required 'iss' claim for -auth=jwt.")
+	jwtAudience   = flag.String("jwt_audience", "", "Required 'aud' claim for -auth=jwt.")
+	jwtJWKSURL    = flag.String("jwt_jwks_url", "", "JWKS URL used to fetch RSA/ECDSA verification keys for -auth=jwt. Mutually exclusive with -jwt_hmac_secret.")
+	jwtHMACSecret = flag.String("jwt_hmac_secret", "", "HMAC verification secret for -auth=jwt, typically sourced from secret.Client by the deployment config. Mutually exclusive with -jwt_jwks_url.")
+
+	backend = flag.String("backend", "buildlet", "Backend used to run gomote sessions: 'buildlet' (the coordinator's reverse buildlet pool), 'swarming' (LUCI swarming bots via rendezvous), or 'hybrid' (route by builder type, swarming for builder types containing '-swarming' and buildlet for everything else).")
+
+	drainTimeout = flag.Duration("drain_timeout", 10*time.Minute, "How long to keep established SSH sessions alive after a shutdown signal before forcibly closing them.")
+
+	objectStoreKind = flag.String("object_store", "gcs", "Object store backend used for gomote file transfers: 'gcs', 's3', or 'local' (for -mode=dev, serves signed URLs off this process's own HTTP mux).")
+	s3Bucket        = flag.String("s3_bucket", "", "Bucket name for -object_store=s3.")
+	s3Region        = flag.String("s3_region", "", "Region for -object_store=s3.")
+	localObjectDir  = flag.String("local_object_dir", "", "Directory to store objects under for -object_store=local. Defaults to a temp directory.")
+	localObjectAddr = flag.String("local_object_addr", "http://localhost:8080", "Externally-reachable base URL of this process, used to build signed URLs for -object_store=local.")
 )
 
 func main() {
@@ -52,8 +69,20 @@ func main() {
 	var sched = schedule.NewScheduler()
 
 	var gomoteBucket string
-	var opts []grpc.ServerOption
-	if *buildEnvName == "" && *mode != "dev" && metadata.OnGCE() {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(access.MetricsUnaryInterceptor()),
+		grpc.ChainStreamInterceptor(access.MetricsStreamInterceptor()),
+	}
+	switch resolveAuthMode(*authMode) {
+	case "jwt":
+		cfg := mustJWTConfig()
+		opts = append(opts, grpc.ChainUnaryInterceptor(access.RequireJWTUnaryInterceptor(cfg)))
+		opts = append(opts, grpc.ChainStreamInterceptor(access.RequireJWTStreamInterceptor(cfg)))
+		if *buildEnvName != "" {
+			env := buildenv.ByProjectID(*buildEnvName)
+			gomoteBucket = env.GomoteTransferBucket
+		}
+	case "iap":
 		projectID, err := metadata.ProjectID()
 		if err != nil {
 			log.Fatalf("metadata.ProjectID() = %v", err)
@@ -64,16 +93,39 @@ func main() {
 		if serviceID = env.IAPServiceID(coordinatorBackend); serviceID == "" {
 			log.Fatalf("unable to retrieve Service ID for backend service=%q", coordinatorBackend)
 		}
-		opts = append(opts, grpc.UnaryInterceptor(access.RequireIAPAuthUnaryInterceptor(access.IAPSkipAudienceValidation)))
-		opts = append(opts, grpc.StreamInterceptor(access.RequireIAPAuthStreamInterceptor(access.IAPSkipAudienceValidation)))
+		opts = append(opts, grpc.ChainUnaryInterceptor(access.RequireIAPAuthUnaryInterceptor(access.IAPSkipAudienceValidation)))
+		opts = append(opts, grpc.ChainStreamInterceptor(access.RequireIAPAuthStreamInterceptor(access.IAPSkipAudienceValidation)))
 	}
 	grpcServer := grpc.NewServer(opts...)
-	gomoteServer := gomote.New(sp, sched, sshCA, gomoteBucket, mustStorageClient(), mustLUCIConfigClient())
-	gomotepb.RegisterGomoteServiceServer(grpcServer, gomoteServer)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/reverse", pool.HandleReverse)
-	mux.HandleFunc("/", grpcHandlerFunc(grpcServer, handleStatus)) // Serve a status page.
+	var hybrid *hybridGomoteService
+	switch *backend {
+	case "buildlet":
+		gomotepb.RegisterGomoteServiceServer(grpcServer, gomote.New(sp, sched, sshCA, gomoteBucket, mustObjectStore(mux, gomoteBucket), mustLUCIConfigClient()))
+		mux.HandleFunc("/reverse", pool.HandleReverse)
+	case "swarming":
+		swarmingServer := gomote.NewSwarmingServer(sp, sched, sshCA, mustRendezvous())
+		gomotepb.RegisterGomoteServiceServer(grpcServer, swarmingServer)
+		mux.HandleFunc("/reverse", swarmingServer.HandleReverse)
+	case "hybrid":
+		buildletServer := gomote.New(sp, sched, sshCA, gomoteBucket, mustObjectStore(mux, gomoteBucket), mustLUCIConfigClient())
+		swarmingServer := gomote.NewSwarmingServer(sp, sched, sshCA, mustRendezvous())
+		hybrid = newHybridGomoteService(buildletServer, swarmingServer)
+		gomotepb.RegisterGomoteServiceServer(grpcServer, hybrid)
+		mux.HandleFunc("/reverse", hybridReverseHandler(pool.HandleReverse, swarmingServer.HandleReverse))
+	default:
+		log.Fatalf("unknown -backend %q", *backend)
+	}
+	health := newHealthState()
+	drain := &drainState{}
+	hist := newSessionHistory()
+	status := newStatusServer(sp, sched, hist, drain)
+	mux.HandleFunc("/statusz", status.handleStatusz)
+	mux.HandleFunc("/debug/sessions.json", status.handleSessionsJSON)
+	mux.HandleFunc("/metrics", handleMetrics(sp, sched))
+	mux.HandleFunc("/healthz", health.ServeHTTP)
+	mux.HandleFunc("/", grpcHandlerFunc(grpcServer, status.handleStatusz))
 
 	configureSSHServer := func() (*remote.SSHServer, error) {
 		if *privateKey != "" && *publicKey != "" {
@@ -97,14 +149,61 @@ func main() {
 			err := sshServ.ListenAndServe()
 			log.Printf("SSH server ended with error: %v", err)
 		}()
-		defer func() {
-			err := sshServ.Close()
-			if err != nil {
-				log.Printf("unable to close SSH server: %s", err)
-			}
-		}()
 	}
-	log.Fatalln(https.ListenAndServe(context.Background(), mux))
+
+	var forgetSession func(gomoteID string)
+	if hybrid != nil {
+		forgetSession = hybrid.forget
+	}
+
+	serveCtx, serveCancel := context.WithCancel(context.Background())
+	go hist.poll(serveCtx, sp, drain, forgetSession)
+	go awaitShutdownSignal(serveCancel, grpcServer, sshServ, sp, health, drain)
+	log.Fatalln(https.ListenAndServe(serveCtx, mux))
+}
+
+// resolveAuthMode returns the effective auth mode: explicit always wins,
+// otherwise IAP is used on GCE and no auth is required anywhere else.
+func resolveAuthMode(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if *buildEnvName == "" && *mode != "dev" && metadata.OnGCE() {
+		return "iap"
+	}
+	return ""
+}
+
+// mustJWTConfig builds the access.JWTConfig for -auth=jwt from flags alone,
+// exiting the process if the flags are missing or contradictory. The HMAC
+// secret is used exactly as given in -jwt_hmac_secret; it is not looked up
+// through secret.Client here.
+func mustJWTConfig() access.JWTConfig {
+	if *jwtIssuer == "" || *jwtAudience == "" {
+		log.Fatalf("-auth=jwt requires -jwt_issuer and -jwt_audience")
+	}
+	cfg := access.JWTConfig{Issuer: *jwtIssuer, Audience: *jwtAudience}
+	switch {
+	case *jwtJWKSURL != "" && *jwtHMACSecret != "":
+		log.Fatalf("-jwt_jwks_url and -jwt_hmac_secret are mutually exclusive")
+	case *jwtJWKSURL != "":
+		cfg.JWKSURL = *jwtJWKSURL
+	case *jwtHMACSecret != "":
+		cfg.HMACSecret = []byte(*jwtHMACSecret)
+	default:
+		log.Fatalf("-auth=jwt requires either -jwt_jwks_url or -jwt_hmac_secret")
+	}
+	return cfg
+}
+
+func mustRendezvous() *rendezvous.Rendezvous {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := rendezvous.New(ctx, mustLUCIConfigClient())
+	if err != nil {
+		log.Fatalf("unable to create rendezvous client: %s", err)
+	}
+	return r
 }
 
 func mustLUCIConfigClient() *swarmclient.ConfigClient {
@@ -153,12 +252,3 @@ func grpcHandlerFunc(gs *grpc.Server, h http.HandlerFunc) http.HandlerFunc {
 		h(w, r)
 	}
 }
-
-func handleStatus(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
-		http.NotFound(w, r)
-		return
-	}
-	w.Header().Set("Content-Type", "text/plain")
-	fmt.Fprintf(w, "gomote status page placeholder")
-}
@@ -0,0 +1,120 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.20 && (linux || darwin)
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/build/internal/coordinator/remote"
+)
+
+// sessionHistoryLimit is the number of completed sessions kept in memory
+// for /statusz, so operators can see recent flake without shelling into
+// the container.
+const sessionHistoryLimit = 200
+
+// sessionPollInterval is how often poll checks sp.List() for sessions
+// that have disappeared since the last check.
+const sessionPollInterval = 5 * time.Second
+
+// completedSession records a remote.Session that has ended, along with
+// why it ended.
+type completedSession struct {
+	ID          string
+	Owner       string
+	BuilderType string
+	Finished    time.Time
+	ExitReason  string
+}
+
+// sessionHistory is a fixed-size ring buffer of the most recently
+// completed sessions, newest first.
+type sessionHistory struct {
+	mu   sync.Mutex
+	buf  []completedSession
+	next int
+	full bool
+}
+
+func newSessionHistory() *sessionHistory {
+	return &sessionHistory{buf: make([]completedSession, sessionHistoryLimit)}
+}
+
+func (h *sessionHistory) add(cs completedSession) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buf[h.next] = cs
+	h.next = (h.next + 1) % len(h.buf)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// poll records a completedSession every time a session present in an
+// earlier call to sp.List() is absent from a later one, until ctx is
+// done. remote.SessionPool has no completion callback to hook directly,
+// so this is the only way to populate the history short of modifying
+// that package. onGone, if non-nil, is also called with the ID of every
+// session that disappears, for callers (like the hybrid backend
+// dispatcher) that need to clean up their own per-session state.
+//
+// drain, if non-nil, is consulted to tell a shutdown-induced exit from
+// an ordinary one: everything that disappears while draining is
+// recorded as drained, and everything else as expired, so /statusz
+// gives operators a real signal instead of one constant string.
+func (h *sessionHistory) poll(ctx context.Context, sp *remote.SessionPool, drain *drainState, onGone func(id string)) {
+	prev := make(map[string]*remote.Session)
+	ticker := time.NewTicker(sessionPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cur := make(map[string]*remote.Session)
+		for _, s := range sp.List() {
+			cur[s.ID] = s
+		}
+		exitReason := "expired"
+		if drain != nil && drain.snapshot().Draining {
+			exitReason = "drained at shutdown"
+		}
+		for id, s := range prev {
+			if _, ok := cur[id]; !ok {
+				h.add(completedSession{
+					ID:          s.ID,
+					Owner:       s.Owner,
+					BuilderType: s.BuilderType,
+					Finished:    time.Now(),
+					ExitReason:  exitReason,
+				})
+				if onGone != nil {
+					onGone(id)
+				}
+			}
+		}
+		prev = cur
+	}
+}
+
+// recent returns the recorded sessions, most recently completed first.
+func (h *sessionHistory) recent() []completedSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := h.next
+	if h.full {
+		n = len(h.buf)
+	}
+	out := make([]completedSession, n)
+	for i := 0; i < n; i++ {
+		out[i] = h.buf[(h.next-1-i+len(h.buf))%len(h.buf)]
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.20 && (linux || darwin)
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/build/internal/coordinator/remote"
+	"golang.org/x/build/internal/coordinator/schedule"
+)
+
+var (
+	activeSessionsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomote",
+		Name:      "active_sessions",
+		Help:      "Number of live gomote sessions, by builder type.",
+	}, []string{"builder_type"})
+
+	schedulerQueueDepthGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomote",
+		Name:      "scheduler_queue_depth",
+		Help:      "Number of gomote sessions waiting for a scheduler slot.",
+	})
+
+	sshConnectionsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomote",
+		Name:      "ssh_connections_in_flight",
+		Help:      "Number of currently open SSH connections to the gomote SSH server.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(activeSessionsGauge, schedulerQueueDepthGauge, sshConnectionsGauge)
+}
+
+// updateSessionMetrics recomputes the session and scheduler gauges from
+// the current state of sp and sched. It is cheap enough to call on every
+// Prometheus scrape.
+func updateSessionMetrics(sp *remote.SessionPool, sched *schedule.Scheduler) {
+	activeSessionsGauge.Reset()
+	var sshConns int
+	for _, s := range sp.List() {
+		activeSessionsGauge.WithLabelValues(s.BuilderType).Inc()
+		sshConns += s.SSHConnections()
+	}
+	sshConnectionsGauge.Set(float64(sshConns))
+	schedulerQueueDepthGauge.Set(float64(sched.State().QueuedCount()))
+}
+
+// handleMetrics returns an http.HandlerFunc that refreshes the session
+// and scheduler gauges and then serves the Prometheus text exposition
+// format for all registered metrics.
+func handleMetrics(sp *remote.SessionPool, sched *schedule.Scheduler) http.HandlerFunc {
+	h := promhttp.Handler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		updateSessionMetrics(sp, sched)
+		h.ServeHTTP(w, r)
+	}
+}
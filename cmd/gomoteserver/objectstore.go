@@ -0,0 +1,61 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.20 && (linux || darwin)
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/build/internal/gomote"
+)
+
+// localObjectStorePath is where the "local" object store mounts its
+// signed-URL handler; it must stay in sync with the baseURL passed to
+// gomote.NewLocalObjectStore.
+const localObjectStorePath = "/localstore"
+
+// mustObjectStore builds the gomote.ObjectStore selected by
+// -object_store, mounting whatever HTTP handler it needs (only the
+// local backend needs one) on mux. gcsBucket is the bucket used by
+// -object_store=gcs, as resolved from the build environment.
+func mustObjectStore(mux *http.ServeMux, gcsBucket string) gomote.ObjectStore {
+	switch *objectStoreKind {
+	case "gcs":
+		return gomote.NewGCSObjectStore(mustStorageClient(), gcsBucket)
+	case "s3":
+		if *s3Bucket == "" {
+			log.Fatalf("-object_store=s3 requires -s3_bucket")
+		}
+		cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(*s3Region))
+		if err != nil {
+			log.Fatalf("unable to load AWS config: %s", err)
+		}
+		return gomote.NewS3ObjectStore(s3.NewFromConfig(cfg), *s3Bucket)
+	case "local":
+		dir := *localObjectDir
+		if dir == "" {
+			var err error
+			dir, err = os.MkdirTemp("", "gomote-local-objectstore")
+			if err != nil {
+				log.Fatalf("unable to create local object store dir: %s", err)
+			}
+		}
+		store, err := gomote.NewLocalObjectStore(dir, *localObjectAddr+localObjectStorePath)
+		if err != nil {
+			log.Fatalf("unable to create local object store: %s", err)
+		}
+		mux.Handle(localObjectStorePath+"/", http.StripPrefix(localObjectStorePath, store.Handler()))
+		return store
+	default:
+		log.Fatalf("unknown -object_store %q", *objectStoreKind)
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.20 && (linux || darwin)
+
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/build/internal/coordinator/remote"
+	"google.golang.org/grpc"
+)
+
+// drainState tracks the progress of a graceful shutdown, for display on
+// /statusz.
+type drainState struct {
+	mu       sync.Mutex
+	draining bool
+	started  time.Time
+	deadline time.Time
+}
+
+func (d *drainState) begin(timeout time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.draining = true
+	d.started = time.Now()
+	d.deadline = d.started.Add(timeout)
+}
+
+// snapshot is the /statusz-facing view of the drain state.
+type drainSnapshot struct {
+	Draining bool
+	Started  time.Time
+	Deadline time.Time
+}
+
+func (d *drainState) snapshot() drainSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return drainSnapshot{Draining: d.draining, Started: d.started, Deadline: d.deadline}
+}
+
+// healthState backs the /healthz endpoint: healthy until a shutdown
+// signal is received, so load balancers stop routing new traffic the
+// moment a rollout begins.
+type healthState struct {
+	mu      sync.Mutex
+	healthy bool
+}
+
+func newHealthState() *healthState {
+	return &healthState{healthy: true}
+}
+
+func (h *healthState) markUnhealthy() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.healthy = false
+}
+
+func (h *healthState) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	healthy := h.healthy
+	h.mu.Unlock()
+	if !healthy {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(w, "ok")
+}
+
+// awaitShutdownSignal blocks until SIGTERM or SIGINT, then drains grpcServer
+// and sp: it stops accepting new gRPC calls immediately, but keeps
+// already-established SSH sessions (and the SSH listener, so reconnects
+// during a client's own retry logic still succeed) alive until they
+// finish naturally or drainTimeout elapses. Only once the drain is over
+// does it close the SSH listener and any sessions still outstanding. It
+// marks health unhealthy as soon as the signal arrives, and calls
+// shutdownCancel once draining is complete so the caller can stop the HTTP
+// server.
+func awaitShutdownSignal(shutdownCancel context.CancelFunc, grpcServer *grpc.Server, sshServ *remote.SSHServer, sp *remote.SessionPool, health *healthState, drain *drainState) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-sigCh
+	log.Printf("received %s, draining gomote server", sig)
+
+	health.markUnhealthy()
+	drain.begin(*drainTimeout)
+
+	go grpcServer.GracefulStop()
+
+	terminated, drained := waitForSessions(sp, *drainTimeout)
+	log.Printf("shutdown complete: %d sessions drained naturally, %d terminated at the drain deadline", drained, terminated)
+
+	if sshServ != nil {
+		if err := sshServ.Close(); err != nil {
+			log.Printf("unable to close SSH listener during drain: %s", err)
+		}
+	}
+	shutdownCancel()
+}
+
+// waitForSessions polls sp until it is empty or timeout elapses, returning
+// the number of sessions still live at the deadline (terminated) versus the
+// number that ended on their own before then (drained).
+func waitForSessions(sp *remote.SessionPool, timeout time.Duration) (terminated, drained int) {
+	deadline := time.Now().Add(timeout)
+	const pollInterval = 2 * time.Second
+	prevCount := len(sp.List())
+	for time.Now().Before(deadline) {
+		n := len(sp.List())
+		if n < prevCount {
+			drained += prevCount - n
+		}
+		prevCount = n
+		if n == 0 {
+			return 0, drained
+		}
+		time.Sleep(pollInterval)
+	}
+	return prevCount, drained
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.20 && (linux || darwin)
+
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+
+	"golang.org/x/build/internal/coordinator/remote"
+	"golang.org/x/build/internal/coordinator/schedule"
+)
+
+// statusServer renders the /statusz and /debug/sessions.json endpoints
+// from the live session pool and scheduler state.
+type statusServer struct {
+	sp    *remote.SessionPool
+	sched *schedule.Scheduler
+	hist  *sessionHistory
+	drain *drainState
+}
+
+func newStatusServer(sp *remote.SessionPool, sched *schedule.Scheduler, hist *sessionHistory, drain *drainState) *statusServer {
+	return &statusServer{sp: sp, sched: sched, hist: hist, drain: drain}
+}
+
+// sessionView is the JSON- and template-facing projection of a
+// remote.Session, independent of that type's internal fields.
+type sessionView struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	BuilderType string    `json:"builder_type"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+	BytesRead   int64     `json:"bytes_read"`
+	BytesWrite  int64     `json:"bytes_written"`
+}
+
+func sessionsView(sessions []*remote.Session) []sessionView {
+	views := make([]sessionView, len(sessions))
+	for i, s := range sessions {
+		views[i] = sessionView{
+			ID:          s.ID,
+			Owner:       s.Owner,
+			BuilderType: s.BuilderType,
+			Created:     s.Created,
+			Expires:     s.Expires,
+			BytesRead:   s.BytesRead(),
+			BytesWrite:  s.BytesWritten(),
+		}
+	}
+	return views
+}
+
+func (s *statusServer) handleStatusz(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Sessions []sessionView
+		Queued   int
+		Recent   []completedSession
+		Drain    drainSnapshot
+	}{
+		Sessions: sessionsView(s.sp.List()),
+		Queued:   s.sched.State().QueuedCount(),
+		Recent:   s.hist.recent(),
+		Drain:    s.drain.snapshot(),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statuszTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *statusServer) handleSessionsJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sessionsView(s.sp.List())); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var statuszTemplate = template.Must(template.New("statusz").Parse(`<!DOCTYPE html>
+<html>
+<head><title>gomote status</title></head>
+<body>
+<h1>gomote status</h1>
+{{if .Drain.Draining}}<h2 style="color:red">Draining since {{.Drain.Started}}, forcing remaining sessions closed at {{.Drain.Deadline}}</h2>{{end}}
+<h2>Live sessions ({{len .Sessions}})</h2>
+<table border="1" cellpadding="4">
+<tr><th>ID</th><th>Owner</th><th>Builder</th><th>Created</th><th>Expires</th><th>Bytes read</th><th>Bytes written</th></tr>
+{{range .Sessions}}<tr><td>{{.ID}}</td><td>{{.Owner}}</td><td>{{.BuilderType}}</td><td>{{.Created}}</td><td>{{.Expires}}</td><td>{{.BytesRead}}</td><td>{{.BytesWrite}}</td></tr>
+{{end}}</table>
+<h2>Scheduler queue depth: {{.Queued}}</h2>
+<h2>Recently completed</h2>
+<table border="1" cellpadding="4">
+<tr><th>ID</th><th>Owner</th><th>Builder</th><th>Finished</th><th>Exit reason</th></tr>
+{{range .Recent}}<tr><td>{{.ID}}</td><td>{{.Owner}}</td><td>{{.BuilderType}}</td><td>{{.Finished}}</td><td>{{.ExitReason}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
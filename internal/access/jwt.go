@@ -0,0 +1,255 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// JWTConfig configures the JWT bearer authentication mode.
+//
+// Exactly one of HMACSecret or JWKSURL should be set: HMACSecret selects
+// HMAC (HS256/HS384/HS512) verification against a shared secret, while
+// JWKSURL selects RSA (RS256/RS384/RS512) or ECDSA (ES256/ES384/ES512)
+// verification against keys published at a JWKS endpoint.
+type JWTConfig struct {
+	Issuer     string
+	Audience   string
+	HMACSecret []byte
+	JWKSURL    string
+}
+
+type jwtSubjectKey struct{}
+
+// JWTSubject returns the subject of the JWT that authenticated ctx's
+// request, if any. It is populated by RequireJWTUnaryInterceptor and
+// RequireJWTStreamInterceptor.
+func JWTSubject(ctx context.Context) (string, bool) {
+	sub, ok := ctx.Value(jwtSubjectKey{}).(string)
+	return sub, ok
+}
+
+// RequireJWTUnaryInterceptor returns a gRPC unary server interceptor that
+// requires a valid JWT bearer token in the "authorization" metadata, as
+// configured by cfg. On success, the resolved subject is attached to the
+// context and can be read back with JWTSubject.
+func RequireJWTUnaryInterceptor(cfg JWTConfig) grpc.UnaryServerInterceptor {
+	v := newJWTVerifier(cfg)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := v.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RequireJWTStreamInterceptor returns a gRPC stream server interceptor
+// equivalent to RequireJWTUnaryInterceptor.
+func RequireJWTStreamInterceptor(cfg JWTConfig) grpc.StreamServerInterceptor {
+	v := newJWTVerifier(cfg)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := v.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context { return w.ctx }
+
+type jwtVerifier struct {
+	cfg  JWTConfig
+	jwks *jwksCache // nil if using an HMAC secret
+}
+
+func newJWTVerifier(cfg JWTConfig) *jwtVerifier {
+	v := &jwtVerifier{cfg: cfg}
+	if cfg.JWKSURL != "" {
+		v.jwks = newJWKSCache(cfg.JWKSURL, 10*time.Minute)
+	}
+	return v
+}
+
+func (v *jwtVerifier) authenticate(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+	raw, err := bearerToken(md)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	sub, err := v.verify(raw)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %s", err)
+	}
+	return context.WithValue(ctx, jwtSubjectKey{}, sub), nil
+}
+
+func bearerToken(md metadata.MD) (string, error) {
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return "", errors.New("no authorization header")
+	}
+	const prefix = "Bearer "
+	if !strings.HasPrefix(vals[0], prefix) {
+		return "", errors.New("authorization header is not a bearer token")
+	}
+	return strings.TrimPrefix(vals[0], prefix), nil
+}
+
+// verify parses and validates raw, returning the resolved subject.
+func (v *jwtVerifier) verify(raw string) (string, error) {
+	claims := jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(raw, &claims, v.keyFunc, jwt.WithIssuer(v.cfg.Issuer), jwt.WithAudience(v.cfg.Audience))
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+func (v *jwtVerifier) keyFunc(tok *jwt.Token) (any, error) {
+	if v.jwks == nil {
+		if _, ok := tok.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v, want HMAC", tok.Method.Alg())
+		}
+		return v.cfg.HMACSecret, nil
+	}
+	kid, _ := tok.Header["kid"].(string)
+	key, err := v.jwks.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	switch tok.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwks key %q is not an RSA public key", kid)
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwks key %q is not an ECDSA public key", kid)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", tok.Method.Alg())
+	}
+	return key, nil
+}
+
+// jwksCache fetches and periodically refreshes a JSON Web Key Set,
+// caching keys by key ID between refreshes.
+type jwksCache struct {
+	url string
+	ttl time.Duration
+
+	mu      sync.Mutex
+	keys    map[string]any
+	fetched time.Time
+}
+
+func newJWKSCache(url string, ttl time.Duration) *jwksCache {
+	return &jwksCache{url: url, ttl: ttl}
+}
+
+func (c *jwksCache) key(kid string) (any, error) {
+	c.mu.Lock()
+	stale := time.Since(c.fetched) > c.ttl
+	c.mu.Unlock()
+	if stale {
+		if err := c.refresh(); err != nil {
+			c.mu.Lock()
+			hasKeys := len(c.keys) > 0
+			c.mu.Unlock()
+			if !hasKeys {
+				return nil, err
+			}
+			// Serve the stale cache rather than fail outright; the next
+			// call will retry the refresh.
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no key with id %q in JWKS", kid)
+	}
+	return key, nil
+}
+
+func (c *jwksCache) refresh() error {
+	resp, err := http.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching JWKS: status %s", resp.Status)
+	}
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding JWKS: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, raw := range set.Keys {
+		kid, key, err := parseJWK(raw)
+		if err != nil {
+			return fmt.Errorf("parsing JWKS key: %w", err)
+		}
+		keys[kid] = key
+	}
+	c.mu.Lock()
+	c.keys = keys
+	c.fetched = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// parseJWK parses a single JSON Web Key, returning its key ID and the
+// resulting *rsa.PublicKey or *ecdsa.PublicKey.
+func parseJWK(raw json.RawMessage) (kid string, key any, err error) {
+	var jwk struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+	}
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return "", nil, err
+	}
+	switch jwk.Kty {
+	case "RSA":
+		pub, err := parseRSAJWK(raw)
+		return jwk.Kid, pub, err
+	case "EC":
+		pub, err := parseECJWK(raw)
+		return jwk.Kid, pub, err
+	default:
+		return "", nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signHS256(t *testing.T, secret []byte, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	s, err := tok.SignedString(secret)
+	if err != nil {
+		t.Fatalf("signing test token: %v", err)
+	}
+	return s
+}
+
+func TestJWTVerifierVerify(t *testing.T) {
+	secret := []byte("test-secret")
+	cfg := JWTConfig{Issuer: "https://issuer.example", Audience: "gomote", HMACSecret: secret}
+	v := newJWTVerifier(cfg)
+
+	validClaims := jwt.RegisteredClaims{
+		Subject:   "user@example.com",
+		Issuer:    cfg.Issuer,
+		Audience:  jwt.ClaimStrings{cfg.Audience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		sub, err := v.verify(signHS256(t, secret, validClaims))
+		if err != nil {
+			t.Fatalf("verify() = %v, want no error", err)
+		}
+		if sub != "user@example.com" {
+			t.Errorf("verify() subject = %q, want %q", sub, "user@example.com")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		claims := validClaims
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		if _, err := v.verify(signHS256(t, secret, claims)); err == nil {
+			t.Fatal("verify() = nil error, want expired token to be rejected")
+		}
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		claims := validClaims
+		claims.Audience = jwt.ClaimStrings{"someone-else"}
+		if _, err := v.verify(signHS256(t, secret, claims)); err == nil {
+			t.Fatal("verify() = nil error, want wrong-audience token to be rejected")
+		}
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		claims := validClaims
+		claims.Issuer = "https://not-the-issuer.example"
+		if _, err := v.verify(signHS256(t, secret, claims)); err == nil {
+			t.Fatal("verify() = nil error, want wrong-issuer token to be rejected")
+		}
+	})
+
+	t.Run("wrong signing method", func(t *testing.T) {
+		tok := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims)
+		s, err := tok.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatalf("signing unsigned test token: %v", err)
+		}
+		if _, err := v.verify(s); err == nil {
+			t.Fatal("verify() = nil error, want alg=none token to be rejected")
+		}
+	})
+
+	t.Run("missing subject", func(t *testing.T) {
+		claims := validClaims
+		claims.Subject = ""
+		if _, err := v.verify(signHS256(t, secret, claims)); err == nil {
+			t.Fatal("verify() = nil error, want subjectless token to be rejected")
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		if _, err := v.verify(signHS256(t, []byte("not-the-secret"), validClaims)); err == nil {
+			t.Fatal("verify() = nil error, want token signed with the wrong secret to be rejected")
+		}
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package access
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+)
+
+// RPCLatency is a histogram of gRPC handler latency in seconds, labeled
+// by the full method name. It is registered with prometheus.DefaultRegisterer
+// when this package is initialized.
+var RPCLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "gomote",
+	Name:      "rpc_latency_seconds",
+	Help:      "Latency of gomote gRPC calls, by method.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method"})
+
+func init() {
+	prometheus.MustRegister(RPCLatency)
+}
+
+// MetricsUnaryInterceptor returns a gRPC unary server interceptor that
+// records each call's latency in RPCLatency.
+func MetricsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		RPCLatency.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// MetricsStreamInterceptor returns a gRPC stream server interceptor
+// equivalent to MetricsUnaryInterceptor.
+func MetricsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		RPCLatency.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
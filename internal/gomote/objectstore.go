@@ -0,0 +1,107 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomote
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// ObjectStore is the object storage backend gomote uses to transfer
+// files to and from a session: build artifacts are uploaded via a
+// signed PUT URL and fetched back via a signed GET URL.
+//
+// Implementations: GCS (the original, still the default in prod), S3,
+// and a local-filesystem backend for -mode=dev.
+type ObjectStore interface {
+	// SignedPutURL returns a URL an untrusted client can use to PUT
+	// object data, valid for the given duration.
+	SignedPutURL(ctx context.Context, object string, expires time.Duration) (string, error)
+
+	// SignedGetURL returns a URL an untrusted client can use to GET
+	// object data, valid for the given duration.
+	SignedGetURL(ctx context.Context, object string, expires time.Duration) (string, error)
+
+	// Object returns a handle usable to read or delete the named
+	// object from within the gomote server process itself.
+	Object(name string) ObjectHandle
+}
+
+// PostPolicySigner is an optional capability of an ObjectStore: a signed
+// POST policy lets a buildlet upload an object directly to the store
+// without holding store credentials. Only the GCS backend supports this
+// natively, so it's kept out of ObjectStore itself; callers on the
+// reverse-buildlet-pool push path should type-assert for it and fall
+// back to SignedPutURL when it's absent.
+type PostPolicySigner interface {
+	GenerateSignedPostPolicyV4(ctx context.Context, object string, expires time.Duration) (*storage.PostPolicyV4, error)
+}
+
+// ObjectHandle is a single object within an ObjectStore.
+type ObjectHandle interface {
+	NewReader(ctx context.Context) (ObjectReader, error)
+	Delete(ctx context.Context) error
+}
+
+// ObjectReader reads one object's contents.
+type ObjectReader interface {
+	Read(p []byte) (n int, err error)
+	Close() error
+}
+
+// gcsObjectStore is the ObjectStore backed by Google Cloud Storage; this
+// is the original, and remains the default in prod.
+type gcsObjectStore struct {
+	client *storage.Client
+	bucket string
+}
+
+// NewGCSObjectStore returns an ObjectStore backed by the given bucket in
+// Google Cloud Storage.
+func NewGCSObjectStore(client *storage.Client, bucket string) ObjectStore {
+	return &gcsObjectStore{client: client, bucket: bucket}
+}
+
+func (s *gcsObjectStore) bucketHandle() *storage.BucketHandle {
+	return s.client.Bucket(s.bucket)
+}
+
+func (s *gcsObjectStore) SignedPutURL(ctx context.Context, object string, expires time.Duration) (string, error) {
+	return s.bucketHandle().SignedURL(object, &storage.SignedURLOptions{
+		Method:  "PUT",
+		Expires: time.Now().Add(expires),
+	})
+}
+
+func (s *gcsObjectStore) SignedGetURL(ctx context.Context, object string, expires time.Duration) (string, error) {
+	return s.bucketHandle().SignedURL(object, &storage.SignedURLOptions{
+		Method:  "GET",
+		Expires: time.Now().Add(expires),
+	})
+}
+
+func (s *gcsObjectStore) GenerateSignedPostPolicyV4(ctx context.Context, object string, expires time.Duration) (*storage.PostPolicyV4, error) {
+	return s.bucketHandle().GenerateSignedPostPolicyV4(object, &storage.PostPolicyV4Options{
+		Expires: time.Now().Add(expires),
+	})
+}
+
+func (s *gcsObjectStore) Object(name string) ObjectHandle {
+	return &gcsObjectHandle{obj: s.bucketHandle().Object(name)}
+}
+
+type gcsObjectHandle struct {
+	obj *storage.ObjectHandle
+}
+
+func (h *gcsObjectHandle) NewReader(ctx context.Context) (ObjectReader, error) {
+	return h.obj.NewReader(ctx)
+}
+
+func (h *gcsObjectHandle) Delete(ctx context.Context) error {
+	return h.obj.Delete(ctx)
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomote
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalObjectStore is an ObjectStore backed by a directory on the local
+// filesystem, for running the gomote server in -mode=dev without a GCS
+// emulator. "Signed" URLs are HMAC-signed paths served by the handler
+// returned by Handler, mounted on the same mux as the rest of the dev
+// server.
+type LocalObjectStore struct {
+	dir     string
+	baseURL string // e.g. "http://localhost:8080/localstore"
+	signKey []byte
+}
+
+// NewLocalObjectStore returns an ObjectStore that stores objects under
+// dir on the local filesystem. baseURL must be the externally-reachable
+// URL prefix that Handler will be mounted at, e.g.
+// "http://localhost:8080/localstore".
+func NewLocalObjectStore(dir, baseURL string) (*LocalObjectStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating local object store dir: %w", err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating local object store signing key: %w", err)
+	}
+	return &LocalObjectStore{dir: dir, baseURL: baseURL, signKey: key}, nil
+}
+
+func (s *LocalObjectStore) sign(object, method string, expires time.Time) string {
+	mac := hmac.New(sha256.New, s.signKey)
+	fmt.Fprintf(mac, "%s:%s:%d", method, object, expires.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalObjectStore) signedURL(object, method string, expires time.Duration) string {
+	exp := time.Now().Add(expires)
+	v := url.Values{
+		"method":  {method},
+		"expires": {strconv.FormatInt(exp.Unix(), 10)},
+		"sig":     {s.sign(object, method, exp)},
+	}
+	return fmt.Sprintf("%s/%s?%s", s.baseURL, url.PathEscape(object), v.Encode())
+}
+
+func (s *LocalObjectStore) SignedPutURL(ctx context.Context, object string, expires time.Duration) (string, error) {
+	return s.signedURL(object, http.MethodPut, expires), nil
+}
+
+func (s *LocalObjectStore) SignedGetURL(ctx context.Context, object string, expires time.Duration) (string, error) {
+	return s.signedURL(object, http.MethodGet, expires), nil
+}
+
+func (s *LocalObjectStore) Object(name string) ObjectHandle {
+	return &localObjectHandle{path: filepath.Join(s.dir, filepath.FromSlash(name))}
+}
+
+type localObjectHandle struct {
+	path string
+}
+
+func (h *localObjectHandle) NewReader(ctx context.Context) (ObjectReader, error) {
+	return os.Open(h.path)
+}
+
+func (h *localObjectHandle) Delete(ctx context.Context) error {
+	return os.Remove(h.path)
+}
+
+// Handler returns an http.Handler that serves the signed PUT/GET URLs
+// issued by this store. It must be mounted at the path prefix passed as
+// baseURL to NewLocalObjectStore, with that prefix stripped from the
+// request path (e.g. via http.StripPrefix): the remaining path is taken
+// as the object name.
+func (s *LocalObjectStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		object := r.URL.Path
+		for len(object) > 0 && object[0] == '/' {
+			object = object[1:]
+		}
+		if strings.Contains(object, "..") {
+			http.Error(w, "invalid object name", http.StatusBadRequest)
+			return
+		}
+		q := r.URL.Query()
+		expiresUnix, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad expires", http.StatusBadRequest)
+			return
+		}
+		expires := time.Unix(expiresUnix, 0)
+		if time.Now().After(expires) {
+			http.Error(w, "url expired", http.StatusForbidden)
+			return
+		}
+		if q.Get("method") != r.Method {
+			http.Error(w, "method mismatch", http.StatusForbidden)
+			return
+		}
+		want := s.sign(object, r.Method, expires)
+		if !hmac.Equal([]byte(want), []byte(q.Get("sig"))) {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		path := filepath.Join(s.dir, filepath.FromSlash(object))
+		switch r.Method {
+		case http.MethodPut:
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			f, err := os.Create(path)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer f.Close()
+			if _, err := f.ReadFrom(r.Body); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		case http.MethodGet:
+			http.ServeFile(w, r, path)
+		default:
+			http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalObjectStore(t *testing.T) *LocalObjectStore {
+	t.Helper()
+	s, err := NewLocalObjectStore(t.TempDir(), "http://localhost:8080/localstore")
+	if err != nil {
+		t.Fatalf("NewLocalObjectStore() = %v", err)
+	}
+	return s
+}
+
+func TestLocalObjectStoreRoundTrip(t *testing.T) {
+	s := newTestLocalObjectStore(t)
+	h := http.StripPrefix("/localstore", s.Handler())
+
+	putURL, err := s.SignedPutURL(context.Background(), "a/b.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedPutURL() = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPut, mustPath(t, putURL), strings.NewReader("hello"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	getURL, err := s.SignedGetURL(context.Background(), "a/b.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedGetURL() = %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, mustPath(t, getURL), nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got := rec.Body.String(); got != "hello" {
+		t.Errorf("GET body = %q, want %q", got, "hello")
+	}
+}
+
+func TestLocalObjectStoreHandlerRejectsTamperedURL(t *testing.T) {
+	s := newTestLocalObjectStore(t)
+	h := http.StripPrefix("/localstore", s.Handler())
+	if err := os.WriteFile(filepath.Join(s.dir, "secret.txt"), []byte("secret"), 0600); err != nil {
+		t.Fatalf("writing test object: %v", err)
+	}
+
+	getURL, err := s.SignedGetURL(context.Background(), "secret.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedGetURL() = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(u *url.URL)
+		wantErr bool
+	}{
+		{
+			name:    "unmodified",
+			mutate:  func(u *url.URL) {},
+			wantErr: false,
+		},
+		{
+			name: "wrong method",
+			mutate: func(u *url.URL) {
+				q := u.Query()
+				q.Set("method", http.MethodPut)
+				u.RawQuery = q.Encode()
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			mutate: func(u *url.URL) {
+				q := u.Query()
+				q.Set("expires", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+				u.RawQuery = q.Encode()
+			},
+			wantErr: true,
+		},
+		{
+			name: "bad signature",
+			mutate: func(u *url.URL) {
+				q := u.Query()
+				q.Set("sig", "0000000000000000000000000000000000000000000000000000000000000000")
+				u.RawQuery = q.Encode()
+			},
+			wantErr: true,
+		},
+		{
+			name: "path traversal",
+			mutate: func(u *url.URL) {
+				u.Path = "/localstore/../../../etc/passwd"
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(getURL)
+			if err != nil {
+				t.Fatalf("url.Parse() = %v", err)
+			}
+			tc.mutate(u)
+			req := httptest.NewRequest(http.MethodGet, u.String(), nil)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			gotErr := rec.Code != http.StatusOK
+			if gotErr != tc.wantErr {
+				t.Errorf("status = %d, wantErr = %v", rec.Code, tc.wantErr)
+			}
+		})
+	}
+}
+
+// mustPath strips the scheme and host from a signed URL, since
+// httptest.NewRequest wants a path and RawQuery, not a full URL with a
+// host the test server doesn't actually listen on.
+func mustPath(t *testing.T, signed string) string {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) = %v", signed, err)
+	}
+	return u.Path + "?" + u.RawQuery
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomote
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3ObjectStore is the ObjectStore backed by an S3-compatible bucket, for
+// running gomote outside of GCP.
+type s3ObjectStore struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+}
+
+// NewS3ObjectStore returns an ObjectStore backed by the given bucket in
+// S3 (or an S3-compatible store), using client for object access and
+// presigned URL generation.
+func NewS3ObjectStore(client *s3.Client, bucket string) ObjectStore {
+	return &s3ObjectStore{client: client, presign: s3.NewPresignClient(client), bucket: bucket}
+}
+
+func (s *s3ObjectStore) SignedPutURL(ctx context.Context, object string, expires time.Duration) (string, error) {
+	req, err := s.presign.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(object),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (s *s3ObjectStore) SignedGetURL(ctx context.Context, object string, expires time.Duration) (string, error) {
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(object),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (s *s3ObjectStore) Object(name string) ObjectHandle {
+	return &s3ObjectHandle{client: s.client, bucket: s.bucket, key: name}
+}
+
+type s3ObjectHandle struct {
+	client *s3.Client
+	bucket string
+	key    string
+}
+
+func (h *s3ObjectHandle) NewReader(ctx context.Context) (ObjectReader, error) {
+	out, err := h.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(h.bucket),
+		Key:    aws.String(h.key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (h *s3ObjectHandle) Delete(ctx context.Context) error {
+	_, err := h.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(h.bucket),
+		Key:    aws.String(h.key),
+	})
+	return err
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomote
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/build/internal/coordinator/remote"
+	"golang.org/x/build/internal/coordinator/schedule"
+	gomotepb "golang.org/x/build/internal/gomote/protos"
+	"golang.org/x/build/internal/swarmclient"
+)
+
+// Server is a gomotepb.GomoteServiceServer backed by the coordinator's
+// reverse buildlet pool.
+type Server struct {
+	gomotepb.UnimplementedGomoteServiceServer
+
+	sessions *remote.SessionPool
+	sched    *schedule.Scheduler
+	sshCA    []byte
+	bucket   string
+	store    ObjectStore
+	luci     *swarmclient.ConfigClient
+}
+
+// New returns a Server that schedules sessions onto the reverse buildlet
+// pool, storing file transfers for those sessions in store.
+func New(sessions *remote.SessionPool, sched *schedule.Scheduler, sshCA []byte, bucket string, store ObjectStore, luci *swarmclient.ConfigClient) *Server {
+	return &Server{sessions: sessions, sched: sched, sshCA: sshCA, bucket: bucket, store: store, luci: luci}
+}
+
+// pushURL returns the URL a buildlet should use to upload object: a
+// signed POST policy when the store supports it (GCS), falling back to
+// a signed PUT URL otherwise (S3, local).
+func (s *Server) pushURL(ctx context.Context, object string, expires time.Duration) (string, error) {
+	if signer, ok := s.store.(PostPolicySigner); ok {
+		policy, err := signer.GenerateSignedPostPolicyV4(ctx, object, expires)
+		if err != nil {
+			return "", err
+		}
+		return policy.URL, nil
+	}
+	return s.store.SignedPutURL(ctx, object, expires)
+}
+
+func (s *Server) Instances(ctx context.Context, req *gomotepb.InstancesRequest) (*gomotepb.InstancesResponse, error) {
+	var instances []*gomotepb.Instance
+	for _, sess := range s.sessions.List() {
+		instances = append(instances, &gomotepb.Instance{
+			GomoteId:    sess.ID,
+			BuilderType: sess.BuilderType,
+		})
+	}
+	return &gomotepb.InstancesResponse{Instances: instances}, nil
+}
+
+func (s *Server) CreateInstance(req *gomotepb.CreateInstanceRequest, stream gomotepb.GomoteService_CreateInstanceServer) error {
+	return fmt.Errorf("gomote: buildlet pool session scheduling is implemented by the coordinator package, not reproduced in this excerpt")
+}